@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var temperatureRegexp = regexp.MustCompile(`(?s).*YES.*t=(-?[0-9]+)`)
+
+// OnewireBus abstracts reading 1-wire devices so device-type parsers
+// don't care whether they come from the local kernel w1 sysfs tree or a
+// remote owserver instance.
+type OnewireBus interface {
+	// ListDevices returns the IDs of the devices present on the bus.
+	ListDevices() ([]string, error)
+	// ReadAttribute returns the value of a logical attribute (e.g.
+	// "temperature", "vdd", "vad", "counter.A", "gpio.3") for a device,
+	// translated to whatever the underlying transport actually calls it.
+	ReadAttribute(deviceID, attribute string) (string, error)
+}
+
+// sysfsBus reads devices from the kernel w1 sysfs tree, e.g.
+// /sys/bus/w1/devices/28-0000061f2eba/w1_slave.
+type sysfsBus struct {
+	devicePath  string
+	retryPolicy ReadRetryPolicy
+}
+
+func newSysfsBus(devicePath string, retryPolicy ReadRetryPolicy) *sysfsBus {
+	return &sysfsBus{devicePath: devicePath, retryPolicy: retryPolicy}
+}
+
+func (b *sysfsBus) ListDevices() ([]string, error) {
+	return listOnewireDevices(b.devicePath)
+}
+
+func (b *sysfsBus) ReadAttribute(deviceID, attribute string) (string, error) {
+	if attribute == "temperature" && familyCode(deviceID) == "28" {
+		return readOnewireTemperatureAttribute(b.devicePath, deviceID, b.retryPolicy)
+	}
+
+	switch {
+	case attribute == "counter.A":
+		return readSysfsAttributeFile(b.devicePath, deviceID, "counters.A")
+	case attribute == "counter.B":
+		return readSysfsAttributeFile(b.devicePath, deviceID, "counters.B")
+	case strings.HasPrefix(attribute, "gpio."):
+		return readSysfsGPIOPin(b.devicePath, deviceID, attribute)
+	default:
+		return readSysfsAttributeFile(b.devicePath, deviceID, attribute)
+	}
+}
+
+// newOnewireBus constructs the configured read backend. sysfsAddress is
+// used for the "sysfs" backend and owserverAddress for the "owserver"
+// backend.
+func newOnewireBus(backend, sysfsAddress, owserverAddress string, retryPolicy ReadRetryPolicy) (OnewireBus, error) {
+	switch backend {
+	case "", "sysfs":
+		return newSysfsBus(sysfsAddress, retryPolicy), nil
+	case "owserver":
+		return newOwserverBus(owserverAddress, retryPolicy), nil
+	default:
+		return nil, fmt.Errorf("unknown onewire backend %q", backend)
+	}
+}
+
+func listOnewireDevices(devicePath string) ([]string, error) {
+	entries, err := ioutil.ReadDir(devicePath)
+	if err != nil {
+		log.Errorf("Can't read device directory %v", err)
+		return nil, err
+	}
+
+	var devices []string
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), "w1_bus_master1") {
+			continue
+		}
+		devices = append(devices, entry.Name())
+		log.Infof("Device found: %s", entry.Name())
+	}
+
+	return devices, nil
+}
+
+// readOnewireTemperatureAttribute reads a DS18B20-style w1_slave file,
+// retrying I/O failures and CRC failures under their own, independently
+// budgeted policies, and returns the parsed Celsius value as a string.
+func readOnewireTemperatureAttribute(devicePath, deviceID string, policy ReadRetryPolicy) (string, error) {
+	devicePayloadFile := fmt.Sprintf("%s%s/w1_slave", devicePath, deviceID)
+
+	var lastErr error
+	for ioAttempts, crcAttempts := 0, 0; ; {
+		buffer, err := ioutil.ReadFile(devicePayloadFile)
+		if err != nil {
+			log.WithFields(log.Fields{"devicePayloadFile": devicePayloadFile}).Error("Error reading Device")
+			lastErr = &ReadError{Reason: ReasonIO, Err: err}
+			ioAttempts++
+			if ioAttempts >= policy.IO.MaxAttempts {
+				return "", lastErr
+			}
+			time.Sleep(policy.IO.Backoff(ioAttempts - 1))
+			continue
+		}
+
+		match := temperatureRegexp.FindStringSubmatch(string(buffer))
+		if len(match) > 0 {
+			rawValue, err := strconv.ParseFloat(match[1], 64)
+			if err != nil {
+				return "", &ReadError{Reason: ReasonCRC, Err: err}
+			}
+			return strconv.FormatFloat(rawValue/1000, 'f', -1, 64), nil
+		}
+
+		lastErr = &ReadError{Reason: ReasonCRC, Err: errors.New("CRC check failed or sensor not ready")}
+		crcAttempts++
+		if crcAttempts >= policy.CRC.MaxAttempts {
+			return "", lastErr
+		}
+		log.WithFields(log.Fields{"deviceID": deviceID, "hostname": hostname}).Warning("Retrying read")
+		time.Sleep(policy.CRC.Backoff(crcAttempts - 1))
+	}
+}
+
+// readSysfsAttributeFile reads a single attribute file from a device's
+// sysfs directory, e.g. .../26-0000061f2eba/vdd.
+func readSysfsAttributeFile(devicePath, deviceID, attribute string) (string, error) {
+	path := fmt.Sprintf("%s%s/%s", devicePath, deviceID, attribute)
+	buffer, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", &ReadError{Reason: ReasonIO, Err: err}
+	}
+	return strings.TrimSpace(string(buffer)), nil
+}
+
+// readSysfsGPIOPin decodes one pin out of a DS2408's "output" sysfs
+// attribute, which the w1_ds2408 kernel driver exposes as a single
+// packed byte (0-255) rather than one file per pin.
+func readSysfsGPIOPin(devicePath, deviceID, attribute string) (string, error) {
+	pin, err := strconv.Atoi(strings.TrimPrefix(attribute, "gpio."))
+	if err != nil {
+		return "", fmt.Errorf("invalid gpio attribute %q", attribute)
+	}
+
+	raw, err := readSysfsAttributeFile(devicePath, deviceID, "output")
+	if err != nil {
+		return "", err
+	}
+
+	state, err := strconv.Atoi(raw)
+	if err != nil {
+		return "", &ReadError{Reason: ReasonCRC, Err: fmt.Errorf("parsing DS2408 output byte %q: %w", raw, err)}
+	}
+
+	return strconv.Itoa((state >> uint(pin)) & 1), nil
+}
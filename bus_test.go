@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeDeviceFile(t *testing.T, devicePath, deviceID, attribute, content string) {
+	t.Helper()
+	dir := filepath.Join(devicePath, deviceID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("MkdirAll(%s): %v", dir, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, attribute), []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile(%s): %v", attribute, err)
+	}
+}
+
+func testRetryPolicy() ReadRetryPolicy {
+	return ReadRetryPolicy{
+		IO:  RetryPolicy{MaxAttempts: 1, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1},
+		CRC: RetryPolicy{MaxAttempts: 1, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1},
+	}
+}
+
+func TestSysfsBusReadAttributeTemperature(t *testing.T) {
+	devicePath := t.TempDir() + "/"
+	writeDeviceFile(t, devicePath, "28-0000061f2eba", "w1_slave", "a1 01 4b 46 7f ff 0c 10 5c : crc=5c YES\na1 01 4b 46 7f ff 0c 10 5c t=26062\n")
+
+	bus := newSysfsBus(devicePath, testRetryPolicy())
+	value, err := bus.ReadAttribute("28-0000061f2eba", "temperature")
+	if err != nil {
+		t.Fatalf("ReadAttribute: %v", err)
+	}
+	if value != "26.062" {
+		t.Errorf("got %q, want %q", value, "26.062")
+	}
+}
+
+func TestSysfsBusReadAttributeVddVad(t *testing.T) {
+	devicePath := t.TempDir() + "/"
+	writeDeviceFile(t, devicePath, "26-0000061f2eba", "vdd", "4.98")
+	writeDeviceFile(t, devicePath, "26-0000061f2eba", "vad", "0.825")
+
+	bus := newSysfsBus(devicePath, testRetryPolicy())
+
+	vdd, err := bus.ReadAttribute("26-0000061f2eba", "vdd")
+	if err != nil || vdd != "4.98" {
+		t.Errorf("vdd = %q, %v; want 4.98, nil", vdd, err)
+	}
+
+	vad, err := bus.ReadAttribute("26-0000061f2eba", "vad")
+	if err != nil || vad != "0.825" {
+		t.Errorf("vad = %q, %v; want 0.825, nil", vad, err)
+	}
+}
+
+func TestSysfsBusReadAttributeGPIOPin(t *testing.T) {
+	devicePath := t.TempDir() + "/"
+	// 170 = 0b10101010: even pins low, odd pins high.
+	writeDeviceFile(t, devicePath, "29-0000061f2eba", "output", "170")
+
+	bus := newSysfsBus(devicePath, testRetryPolicy())
+
+	cases := map[string]string{"gpio.0": "0", "gpio.1": "1", "gpio.2": "0", "gpio.7": "1"}
+	for attribute, want := range cases {
+		got, err := bus.ReadAttribute("29-0000061f2eba", attribute)
+		if err != nil {
+			t.Errorf("ReadAttribute(%s): %v", attribute, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ReadAttribute(%s) = %q, want %q", attribute, got, want)
+		}
+	}
+}
+
+func TestSysfsBusReadAttributeCounterMissingFile(t *testing.T) {
+	devicePath := t.TempDir() + "/"
+	writeDeviceFile(t, devicePath, "1D-0000061f2eba", "placeholder", "")
+
+	bus := newSysfsBus(devicePath, testRetryPolicy())
+	if _, err := bus.ReadAttribute("1D-0000061f2eba", "counter.A"); err == nil {
+		t.Error("expected an error reading counter.A on sysfs with no counters.A file, got nil")
+	}
+}
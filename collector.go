@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// OnewireCollector is a prometheus.Collector that reads the 1-wire bus
+// fresh on every call to Collect, rather than relying on a value cached
+// by a background goroutine. This keeps /metrics accurate for the
+// scrape that triggered it and lets Prometheus's own scrape_duration
+// metric reflect the true cost of a read.
+type OnewireCollector struct {
+	bus        OnewireBus
+	fahrenheit bool
+	labels     map[string]DeviceLabels
+	target     string
+
+	temperatureCDesc   *prometheus.Desc
+	temperatureFDesc   *prometheus.Desc
+	humidityDesc       *prometheus.Desc
+	voltageDesc        *prometheus.Desc
+	currentDesc        *prometheus.Desc
+	counterDesc        *prometheus.Desc
+	gpioDesc           *prometheus.Desc
+	upDesc             *prometheus.Desc
+	scrapeDurationDesc *prometheus.Desc
+	scrapeErrorsTotal  *prometheus.CounterVec
+	readErrorsTotal    *prometheus.CounterVec
+}
+
+// NewOnewireCollector returns a collector that lists devices on bus and
+// reads each one on every Collect call. labels maps a device ID to the
+// human-friendly location/alias configured for it; it may be nil when
+// no such labels are configured. target identifies which /json bucket
+// this collector's scrapes feed; it is "" in single-bus mode.
+func NewOnewireCollector(bus OnewireBus, fahrenheit bool, labels map[string]DeviceLabels, target string) *OnewireCollector {
+	deviceLabelNames := []string{"device_id", "hostname", "family", "location", "alias"}
+	channelLabelNames := append(append([]string{}, deviceLabelNames...), "channel")
+
+	return &OnewireCollector{
+		bus:        bus,
+		fahrenheit: fahrenheit,
+		labels:     labels,
+		target:     target,
+
+		temperatureCDesc: prometheus.NewDesc(
+			"onewire_temperature_c",
+			"Onewire Temperature Sensor Value in Celsius.",
+			deviceLabelNames, nil,
+		),
+		temperatureFDesc: prometheus.NewDesc(
+			"onewire_temperature_f",
+			"Onewire Temperature Sensor Value in Fahrenheit.",
+			deviceLabelNames, nil,
+		),
+		humidityDesc: prometheus.NewDesc(
+			"onewire_humidity_percent",
+			"Onewire Humidity Sensor Value in percent relative humidity.",
+			deviceLabelNames, nil,
+		),
+		voltageDesc: prometheus.NewDesc(
+			"onewire_voltage_volts",
+			"Onewire Voltage Sensor Value in volts.",
+			deviceLabelNames, nil,
+		),
+		currentDesc: prometheus.NewDesc(
+			"onewire_current_amps",
+			"Onewire Current Sensor Value in amps.",
+			deviceLabelNames, nil,
+		),
+		counterDesc: prometheus.NewDesc(
+			"onewire_counter_total",
+			"Onewire free-running counter total.",
+			channelLabelNames, nil,
+		),
+		gpioDesc: prometheus.NewDesc(
+			"onewire_gpio_state",
+			"Onewire GPIO pin state, 0 or 1.",
+			channelLabelNames, nil,
+		),
+		upDesc: prometheus.NewDesc(
+			"onewire_up",
+			"Whether the last scrape of the device succeeded (1) or failed (0).",
+			[]string{"device_id"}, nil,
+		),
+		scrapeDurationDesc: prometheus.NewDesc(
+			"onewire_scrape_duration_seconds",
+			"Time it took to read the device during the last scrape.",
+			[]string{"device_id"}, nil,
+		),
+		scrapeErrorsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "onewire_scrape_errors_total",
+				Help: "Total number of failed device reads, by device.",
+			},
+			[]string{"device_id"},
+		),
+		readErrorsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "onewire_read_errors_total",
+				Help: "Total number of failed device reads, by device and failure reason (io, crc, or unknown).",
+			},
+			[]string{"device_id", "reason"},
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *OnewireCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.temperatureCDesc
+	if c.fahrenheit {
+		ch <- c.temperatureFDesc
+	}
+	ch <- c.humidityDesc
+	ch <- c.voltageDesc
+	ch <- c.currentDesc
+	ch <- c.counterDesc
+	ch <- c.gpioDesc
+	ch <- c.upDesc
+	ch <- c.scrapeDurationDesc
+	c.scrapeErrorsTotal.Describe(ch)
+	c.readErrorsTotal.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *OnewireCollector) Collect(ch chan<- prometheus.Metric) {
+	devices, err := c.bus.ListDevices()
+	if err != nil {
+		log.Error("Error listing Onewire devices")
+		c.scrapeErrorsTotal.Collect(ch)
+		return
+	}
+
+	scraped := make([]sensor, 0, len(devices))
+
+	for _, deviceID := range devices {
+		start := time.Now()
+		readings, err := parseDevice(c.bus, deviceID)
+		ch <- prometheus.MustNewConstMetric(c.scrapeDurationDesc, prometheus.GaugeValue, time.Since(start).Seconds(), deviceID)
+
+		if err != nil {
+			log.WithFields(log.Fields{"deviceID": deviceID}).Error("Error reading from device")
+			c.scrapeErrorsTotal.WithLabelValues(deviceID).Inc()
+			c.readErrorsTotal.WithLabelValues(deviceID, readErrorReason(err)).Inc()
+			ch <- prometheus.MustNewConstMetric(c.upDesc, prometheus.GaugeValue, 0, deviceID)
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.upDesc, prometheus.GaugeValue, 1, deviceID)
+
+		deviceLabels := c.labels[deviceID]
+		family := familyCode(deviceID)
+
+		for _, reading := range readings {
+			c.collectReading(ch, deviceID, family, deviceLabels, reading)
+
+			sensorID := deviceID
+			if reading.Channel != "" {
+				sensorID = deviceID + "." + reading.Channel
+			}
+			scraped = append(scraped, sensor{SensorID: sensorID, SensorType: reading.Kind, SensorValue: reading.Value})
+		}
+	}
+
+	c.scrapeErrorsTotal.Collect(ch)
+	c.readErrorsTotal.Collect(ch)
+
+	// Keep the JSON endpoint in sync with whatever this target's last
+	// scrape saw.
+	setSensors(c.target, scraped)
+}
+
+// readErrorReason extracts the classified reason from a *ReadError, or
+// "unknown" for errors that didn't come through that path (e.g. the
+// family isn't registered at all).
+func readErrorReason(err error) string {
+	var readErr *ReadError
+	if errors.As(err, &readErr) {
+		return readErr.Reason
+	}
+	return "unknown"
+}
+
+func (c *OnewireCollector) collectReading(ch chan<- prometheus.Metric, deviceID, family string, deviceLabels DeviceLabels, reading Reading) {
+	labelValues := []string{deviceID, hostname, family, deviceLabels.Location, deviceLabels.Alias}
+
+	switch reading.Kind {
+	case "temperature":
+		ch <- prometheus.MustNewConstMetric(c.temperatureCDesc, prometheus.GaugeValue, reading.Value, labelValues...)
+		if c.fahrenheit {
+			fahrenheit := math.Round((reading.Value*1.8+32)*100) / 100
+			ch <- prometheus.MustNewConstMetric(c.temperatureFDesc, prometheus.GaugeValue, fahrenheit, labelValues...)
+		}
+	case "humidity":
+		ch <- prometheus.MustNewConstMetric(c.humidityDesc, prometheus.GaugeValue, reading.Value, labelValues...)
+	case "voltage":
+		ch <- prometheus.MustNewConstMetric(c.voltageDesc, prometheus.GaugeValue, reading.Value, labelValues...)
+	case "current":
+		ch <- prometheus.MustNewConstMetric(c.currentDesc, prometheus.GaugeValue, reading.Value, labelValues...)
+	case "counter":
+		ch <- prometheus.MustNewConstMetric(c.counterDesc, prometheus.CounterValue, reading.Value, append(labelValues, reading.Channel)...)
+	case "gpio":
+		ch <- prometheus.MustNewConstMetric(c.gpioDesc, prometheus.GaugeValue, reading.Value, append(labelValues, reading.Channel)...)
+	default:
+		log.WithFields(log.Fields{"deviceID": deviceID, "kind": reading.Kind}).Warning("Unknown reading kind")
+	}
+
+	log.WithFields(log.Fields{"deviceID": deviceID, "kind": reading.Kind, "channel": reading.Channel, "value": reading.Value, "hostname": hostname}).Info("Value read from device")
+}
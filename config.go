@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DeviceLabels holds human-friendly labels that get attached to the
+// metrics emitted for a single device ID, so dashboards don't have to
+// key off an opaque hex address.
+type DeviceLabels struct {
+	Location string `yaml:"location,omitempty"`
+	Alias    string `yaml:"alias,omitempty"`
+}
+
+// TargetConfig describes a single scrapeable 1-wire bus: where to find
+// it and how to label the devices on it. Module selects the read
+// backend ("sysfs" or "owserver", default "sysfs"); Address is a local
+// w1 device path for "sysfs" or a host:port for "owserver".
+type TargetConfig struct {
+	Name    string                  `yaml:"name"`
+	Module  string                  `yaml:"module"`
+	Address string                  `yaml:"address"`
+	Devices map[string]DeviceLabels `yaml:"devices"`
+}
+
+// Config is the top-level layout of the --config.file YAML: the list of
+// targets this exporter instance can scrape via /metrics?target=<name>.
+type Config struct {
+	Targets []TargetConfig `yaml:"targets"`
+}
+
+// LoadConfig reads and parses a multi-target config file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config file: %w", err)
+	}
+
+	seenNames := make(map[string]bool, len(cfg.Targets))
+	for i, target := range cfg.Targets {
+		if target.Name == "" {
+			return nil, fmt.Errorf("target at index %d is missing a name", i)
+		}
+		if target.Address == "" {
+			return nil, fmt.Errorf("target %q is missing an address", target.Name)
+		}
+		if seenNames[target.Name] {
+			return nil, fmt.Errorf("target %q is configured more than once", target.Name)
+		}
+		seenNames[target.Name] = true
+	}
+
+	return &cfg, nil
+}
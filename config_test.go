@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigValid(t *testing.T) {
+	path := writeConfigFile(t, `
+targets:
+  - name: garage
+    module: sysfs
+    address: /sys/bus/w1/devices/
+    devices:
+      28-0000061f2eba:
+        location: garage
+        alias: outdoor
+  - name: shed
+    module: owserver
+    address: shed.local:4304
+`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(cfg.Targets) != 2 {
+		t.Fatalf("got %d targets, want 2", len(cfg.Targets))
+	}
+	if cfg.Targets[0].Name != "garage" || cfg.Targets[0].Devices["28-0000061f2eba"].Location != "garage" {
+		t.Errorf("garage target not parsed as expected: %+v", cfg.Targets[0])
+	}
+	if cfg.Targets[1].Address != "shed.local:4304" {
+		t.Errorf("shed target address = %q, want shed.local:4304", cfg.Targets[1].Address)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected an error loading a nonexistent config file")
+	}
+}
+
+func TestLoadConfigMissingName(t *testing.T) {
+	path := writeConfigFile(t, `
+targets:
+  - address: /sys/bus/w1/devices/
+`)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected an error for a target missing a name")
+	}
+}
+
+func TestLoadConfigMissingAddress(t *testing.T) {
+	path := writeConfigFile(t, `
+targets:
+  - name: garage
+`)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected an error for a target missing an address")
+	}
+}
+
+func TestLoadConfigDuplicateName(t *testing.T) {
+	path := writeConfigFile(t, `
+targets:
+  - name: garage
+    address: /sys/bus/w1/devices/
+  - name: garage
+    address: garage2.local:4304
+`)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected an error for two targets sharing a name")
+	}
+}
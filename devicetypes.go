@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Reading is a single value read from a device, tagged with the metric
+// kind it should be exported as. Channel distinguishes multiple
+// readings of the same kind from one device, e.g. DS2423 counters A/B
+// or DS2408 GPIO pins; it is empty when a family only has one reading
+// per kind.
+type Reading struct {
+	Kind    string
+	Channel string
+	Value   float64
+}
+
+// DeviceParser reads and decodes every reading a device family exposes.
+type DeviceParser func(bus OnewireBus, deviceID string) ([]Reading, error)
+
+var deviceParsers = map[string]DeviceParser{}
+
+// registerDeviceParser adds a parser for the given family code (the two
+// hex digits before the "-" or "." in a device ID, e.g. "28" for
+// DS18B20).
+func registerDeviceParser(familyCode string, parser DeviceParser) {
+	deviceParsers[familyCode] = parser
+}
+
+func init() {
+	registerDeviceParser("28", parseDS18B20)
+	registerDeviceParser("26", parseDS2438)
+	registerDeviceParser("1D", parseDS2423)
+	registerDeviceParser("29", parseDS2408)
+}
+
+// familyCode extracts the family code from a device ID such as
+// "28-0000061f2eba" or "28.0000061F2EBA".
+func familyCode(deviceID string) string {
+	if idx := strings.IndexAny(deviceID, "-."); idx > 0 {
+		return strings.ToUpper(deviceID[:idx])
+	}
+	return strings.ToUpper(deviceID)
+}
+
+// parseDevice dispatches to the parser registered for deviceID's
+// family, returning an error if the family is unrecognized.
+func parseDevice(bus OnewireBus, deviceID string) ([]Reading, error) {
+	family := familyCode(deviceID)
+	parser, ok := deviceParsers[family]
+	if !ok {
+		return nil, fmt.Errorf("no parser registered for family %q (device %s)", family, deviceID)
+	}
+	return parser(bus, deviceID)
+}
+
+// parseDS18B20 reads a plain temperature sensor.
+func parseDS18B20(bus OnewireBus, deviceID string) ([]Reading, error) {
+	value, err := readAttributeFloat(bus, deviceID, "temperature")
+	if err != nil {
+		return nil, err
+	}
+	return []Reading{{Kind: "temperature", Value: value}}, nil
+}
+
+// parseDS2438 reads a DS2438's temperature and VDD supply voltage, plus
+// humidity when the device has an attached HIH-4000 wired into its VAD
+// channel. The DS2438 itself has no current-sense attribute exposed by
+// either the kernel w1 driver or owserver, so no "current" reading is
+// produced; not every wiring has a humidity sensor attached either, so
+// that reading is skipped rather than failing the whole device.
+func parseDS2438(bus OnewireBus, deviceID string) ([]Reading, error) {
+	var readings []Reading
+
+	temperature, temperatureErr := readAttributeFloat(bus, deviceID, "temperature")
+	if temperatureErr == nil {
+		readings = append(readings, Reading{Kind: "temperature", Value: temperature})
+	}
+
+	vdd, vddErr := readAttributeFloat(bus, deviceID, "vdd")
+	if vddErr == nil {
+		readings = append(readings, Reading{Kind: "voltage", Value: vdd})
+	}
+
+	if vad, err := readAttributeFloat(bus, deviceID, "vad"); err == nil && vddErr == nil && temperatureErr == nil {
+		readings = append(readings, Reading{Kind: "humidity", Value: hih4000Humidity(vad, vdd, temperature)})
+	}
+
+	if len(readings) == 0 {
+		return nil, fmt.Errorf("no readable attributes for DS2438 device %s", deviceID)
+	}
+	return readings, nil
+}
+
+// hih4000Humidity converts a DS2438's VAD channel reading (wired to a
+// Honeywell HIH-4000's analog output) and VDD supply voltage into a
+// temperature-compensated relative humidity percentage, using the
+// linear response and compensation formulas from the HIH-4000
+// datasheet.
+func hih4000Humidity(vad, vdd, temperatureC float64) float64 {
+	sensorRH := (vad/vdd - 0.16) / 0.0062
+	return sensorRH / (1.0546 - 0.00216*temperatureC)
+}
+
+// parseDS2423 reads both of a DS2423's free-running counters.
+func parseDS2423(bus OnewireBus, deviceID string) ([]Reading, error) {
+	var readings []Reading
+	for _, channel := range []string{"A", "B"} {
+		value, err := readAttributeFloat(bus, deviceID, "counter."+channel)
+		if err != nil {
+			continue
+		}
+		readings = append(readings, Reading{Kind: "counter", Channel: channel, Value: value})
+	}
+	if len(readings) == 0 {
+		return nil, fmt.Errorf("no readable counters for DS2423 device %s", deviceID)
+	}
+	return readings, nil
+}
+
+// parseDS2408 reads the state of all 8 GPIO pins on a DS2408.
+func parseDS2408(bus OnewireBus, deviceID string) ([]Reading, error) {
+	var readings []Reading
+	for pin := 0; pin < 8; pin++ {
+		value, err := readAttributeFloat(bus, deviceID, fmt.Sprintf("gpio.%d", pin))
+		if err != nil {
+			continue
+		}
+		readings = append(readings, Reading{Kind: "gpio", Channel: strconv.Itoa(pin), Value: value})
+	}
+	if len(readings) == 0 {
+		return nil, fmt.Errorf("no readable gpio pins for DS2408 device %s", deviceID)
+	}
+	return readings, nil
+}
+
+func readAttributeFloat(bus OnewireBus, deviceID, attribute string) (float64, error) {
+	raw, err := bus.ReadAttribute(deviceID, attribute)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(raw, 64)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// fakeBus serves fixture attribute values for parser tests, without
+// touching the filesystem or network.
+type fakeBus struct {
+	attributes map[string]string
+}
+
+func (b *fakeBus) ListDevices() ([]string, error) { return nil, nil }
+
+func (b *fakeBus) ReadAttribute(deviceID, attribute string) (string, error) {
+	key := deviceID + "/" + attribute
+	value, ok := b.attributes[key]
+	if !ok {
+		return "", &ReadError{Reason: ReasonIO, Err: fmt.Errorf("no fixture for %s", key)}
+	}
+	return value, nil
+}
+
+func TestParseDS18B20(t *testing.T) {
+	bus := &fakeBus{attributes: map[string]string{"28-x/temperature": "23.5"}}
+
+	readings, err := parseDS18B20(bus, "28-x")
+	if err != nil {
+		t.Fatalf("parseDS18B20: %v", err)
+	}
+	if len(readings) != 1 || readings[0].Kind != "temperature" || readings[0].Value != 23.5 {
+		t.Errorf("got %+v, want one temperature reading of 23.5", readings)
+	}
+}
+
+func TestParseDS2438WithHumidity(t *testing.T) {
+	bus := &fakeBus{attributes: map[string]string{
+		"26-x/temperature": "25",
+		"26-x/vdd":         "5.0",
+		"26-x/vad":         "1.0",
+	}}
+
+	readings, err := parseDS2438(bus, "26-x")
+	if err != nil {
+		t.Fatalf("parseDS2438: %v", err)
+	}
+
+	kinds := map[string]float64{}
+	for _, r := range readings {
+		kinds[r.Kind] = r.Value
+	}
+
+	if kinds["temperature"] != 25 {
+		t.Errorf("temperature = %v, want 25", kinds["temperature"])
+	}
+	if kinds["voltage"] != 5.0 {
+		t.Errorf("voltage = %v, want 5.0", kinds["voltage"])
+	}
+	wantHumidity := hih4000Humidity(1.0, 5.0, 25)
+	if math.Abs(kinds["humidity"]-wantHumidity) > 1e-9 {
+		t.Errorf("humidity = %v, want %v", kinds["humidity"], wantHumidity)
+	}
+	if _, ok := kinds["current"]; ok {
+		t.Error("DS2438 has no verifiable current attribute; parser should not emit one")
+	}
+}
+
+func TestParseDS2438WithoutHumiditySensor(t *testing.T) {
+	bus := &fakeBus{attributes: map[string]string{
+		"26-x/temperature": "25",
+		"26-x/vdd":         "5.0",
+	}}
+
+	readings, err := parseDS2438(bus, "26-x")
+	if err != nil {
+		t.Fatalf("parseDS2438: %v", err)
+	}
+	for _, r := range readings {
+		if r.Kind == "humidity" {
+			t.Error("no vad fixture was provided; should not have produced a humidity reading")
+		}
+	}
+}
+
+func TestHIH4000Humidity(t *testing.T) {
+	// At 25C with no compensation needed (1.0546 - 0.00216*25 ~= 1.0),
+	// a VAD of half VDD should land close to the datasheet's ~54% RH.
+	got := hih4000Humidity(2.5, 5.0, 25)
+	want := 54.4
+	if math.Abs(got-want) > 1 {
+		t.Errorf("hih4000Humidity(2.5, 5.0, 25) = %v, want ~%v", got, want)
+	}
+}
+
+func TestParseDS2423PartialCounters(t *testing.T) {
+	bus := &fakeBus{attributes: map[string]string{
+		"1D-x/counter.A": "120",
+	}}
+
+	readings, err := parseDS2423(bus, "1D-x")
+	if err != nil {
+		t.Fatalf("parseDS2423: %v", err)
+	}
+	if len(readings) != 1 || readings[0].Channel != "A" || readings[0].Value != 120 {
+		t.Errorf("got %+v, want one counter reading on channel A of 120", readings)
+	}
+}
+
+func TestParseDS2423NoCounters(t *testing.T) {
+	bus := &fakeBus{attributes: map[string]string{}}
+	if _, err := parseDS2423(bus, "1D-x"); err == nil {
+		t.Error("expected an error when neither counter is readable")
+	}
+}
+
+func TestParseDS2408(t *testing.T) {
+	bus := &fakeBus{attributes: map[string]string{
+		"29-x/gpio.0": "0",
+		"29-x/gpio.1": "1",
+		"29-x/gpio.2": "0",
+		"29-x/gpio.3": "1",
+		"29-x/gpio.4": "0",
+		"29-x/gpio.5": "1",
+		"29-x/gpio.6": "0",
+		"29-x/gpio.7": "1",
+	}}
+
+	readings, err := parseDS2408(bus, "29-x")
+	if err != nil {
+		t.Fatalf("parseDS2408: %v", err)
+	}
+	if len(readings) != 8 {
+		t.Fatalf("got %d readings, want 8", len(readings))
+	}
+	if readings[1].Channel != "1" || readings[1].Value != 1 {
+		t.Errorf("pin 1 = %+v, want Channel=1 Value=1", readings[1])
+	}
+}
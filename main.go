@@ -2,23 +2,21 @@ package main
 
 import (
 	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
-	"math"
 	"net/http"
 	"os"
-	"regexp"
-	"strconv"
-	"strings"
-	"time"
+	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 )
 
+// version is the exporter build version, overridden at build time via
+// -ldflags "-X main.version=...".
+var version = "dev"
+
 type sensor struct {
 	SensorID    string  `json:"sensorid"`
 	SensorType  string  `json:"type"`
@@ -26,25 +24,38 @@ type sensor struct {
 }
 
 var (
-	sensors             []sensor
-	onewireDevicePath   = "/sys/bus/w1/devices/"
-	onewireDeviceList   []string
-	hostname, _         = os.Hostname()
-	listenAddress       = flag.String("web.listen-address", ":8105", "Address and port to expose metrics")
-	metricsPath         = flag.String("web.telemetry-path", "/metrics", "Path under which to expose metrics.")
-	jsonMetricsPath     = flag.String("web.json-path", "/json", "Path under which to expose json metrics.")
-	enableFahrenheit    = flag.Bool("export.fahrenheit", false, "Include Fahrenheit in export.")
-	onewireTemperatureC = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "onewire_temperature_c",
-			Help: "Onewire Temperature Sensor Value in Celsius.",
-		},
-		[]string{
-			"device_id",
-			"hostname",
-		},
-	)
-	onewireTemperatureF *prometheus.GaugeVec
+	// sensorsMu guards sensorsByTarget, which multiple targets'
+	// Collect() calls may write concurrently when /metrics is scraped
+	// for several targets at once. Each target gets its own slot (keyed
+	// by name, or "" in single-bus mode) so one target's scrape can't
+	// clobber another's view in /json.
+	sensorsMu         sync.Mutex
+	sensorsByTarget   = map[string][]sensor{}
+	onewireDevicePath = "/sys/bus/w1/devices/"
+	hostname, _       = os.Hostname()
+
+	listenAddress      = flag.String("web.listen-address", ":8105", "Address and port to expose metrics")
+	metricsPath        = flag.String("web.telemetry-path", "/metrics", "Path under which to expose onewire device metrics.")
+	processMetricsPath = flag.String("web.process-metrics-path", "/metrics/process", "Path under which to expose exporter process and Go runtime metrics.")
+	jsonMetricsPath    = flag.String("web.json-path", "/json", "Path under which to expose json metrics.")
+	enableFahrenheit   = flag.Bool("export.fahrenheit", false, "Include Fahrenheit in export.")
+	configFile         = flag.String("config.file", "", "Path to a YAML file describing multiple scrape targets. When set, /metrics requires a ?target= parameter instead of scraping the local bus.")
+	onewireBackend     = flag.String("onewire.backend", "sysfs", "Read backend to use when --config.file is not set: sysfs or owserver.")
+	owserverAddress    = flag.String("onewire.owserver-address", "localhost:4304", "Address of the owserver instance to read from when --onewire.backend=owserver.")
+
+	defaultRetryPolicy = DefaultReadRetryPolicy()
+
+	retryIOMaxAttempts    = flag.Int("onewire.retry.io-max-attempts", defaultRetryPolicy.IO.MaxAttempts, "Maximum attempts for a device read that fails with an I/O error (file unreadable, device disappeared).")
+	retryIOInitialBackoff = flag.Duration("onewire.retry.io-initial-backoff", defaultRetryPolicy.IO.InitialBackoff, "Initial delay before retrying a failed I/O read.")
+	retryIOMaxBackoff     = flag.Duration("onewire.retry.io-max-backoff", defaultRetryPolicy.IO.MaxBackoff, "Maximum delay between I/O read retries.")
+	retryIOMultiplier     = flag.Float64("onewire.retry.io-multiplier", defaultRetryPolicy.IO.Multiplier, "Backoff multiplier applied after each failed I/O read.")
+	retryIOJitterFraction = flag.Float64("onewire.retry.io-jitter-fraction", defaultRetryPolicy.IO.JitterFraction, "Fraction of the computed I/O backoff to randomly jitter by.")
+
+	retryCRCMaxAttempts    = flag.Int("onewire.retry.crc-max-attempts", defaultRetryPolicy.CRC.MaxAttempts, "Maximum attempts for a device read that fails its CRC check.")
+	retryCRCInitialBackoff = flag.Duration("onewire.retry.crc-initial-backoff", defaultRetryPolicy.CRC.InitialBackoff, "Initial delay before retrying a failed CRC read.")
+	retryCRCMaxBackoff     = flag.Duration("onewire.retry.crc-max-backoff", defaultRetryPolicy.CRC.MaxBackoff, "Maximum delay between CRC read retries.")
+	retryCRCMultiplier     = flag.Float64("onewire.retry.crc-multiplier", defaultRetryPolicy.CRC.Multiplier, "Backoff multiplier applied after each failed CRC read.")
+	retryCRCJitterFraction = flag.Float64("onewire.retry.crc-jitter-fraction", defaultRetryPolicy.CRC.JitterFraction, "Fraction of the computed CRC backoff to randomly jitter by.")
 )
 
 func init() {
@@ -57,39 +68,85 @@ func init() {
 
 	// Only log the warning severity or above.
 	//log.SetLevel(log.WarnLevel)
-	// Parsing command line arguments
-	flag.Parse()
-	// Registers temperature gauges
-	prometheus.MustRegister(onewireTemperatureC)
-	if *enableFahrenheit {
-		onewireTemperatureF = prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: "onewire_temperature_f",
-				Help: "Onewire Temperature Sensor Value in Fahrenheit.",
-			},
-			[]string{
-				"device_id",
-				"hostname",
-			},
-		)
-
-		prometheus.MustRegister(onewireTemperatureF)
-	}
 }
 
 func main() {
+	flag.Parse()
+
 	log.Info("Started")
-	// install net/http handlers
-	http.Handle(*metricsPath, promhttp.Handler())
+
+	retryPolicy := readRetryPolicyFromFlags()
+
+	// Device metrics live on their own registry so that a scrape of
+	// /metrics only ever sees onewire_* series, each read fresh from the
+	// bus for that request.
+	if *configFile != "" {
+		cfg, err := LoadConfig(*configFile)
+		if err != nil {
+			log.Fatalf("Error loading config file: %v", err)
+		}
+		handler, err := multiTargetMetricsHandler(cfg, retryPolicy)
+		if err != nil {
+			log.Fatalf("Error configuring multi-target metrics handler: %v", err)
+		}
+		http.HandleFunc(*metricsPath, handler)
+	} else {
+		bus, err := newOnewireBus(*onewireBackend, onewireDevicePath, *owserverAddress, retryPolicy)
+		if err != nil {
+			log.Fatalf("Error configuring onewire backend: %v", err)
+		}
+
+		deviceRegistry := prometheus.NewRegistry()
+		deviceRegistry.MustRegister(NewOnewireCollector(bus, *enableFahrenheit, nil, ""))
+		http.Handle(*metricsPath, promhttp.HandlerFor(deviceRegistry, promhttp.HandlerOpts{}))
+	}
+
+	// Process/Go runtime metrics and build info are kept on a separate
+	// registry so they don't clutter the device-facing one above.
+	telemetryRegistry := prometheus.NewRegistry()
+	telemetryRegistry.MustRegister(prometheus.NewGoCollector())
+	telemetryRegistry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+	telemetryRegistry.MustRegister(newBuildInfoCollector())
+
+	http.Handle(*processMetricsPath, promhttp.HandlerFor(telemetryRegistry, promhttp.HandlerOpts{}))
 	http.HandleFunc("/", rootPathHandler)
 	http.HandleFunc(*jsonMetricsPath, jsonPathHandler)
 
-	// launch prometheus metrics handler as a goroutine
-	go observeOnewireTemperature()
-	// starts http listener
 	log.WithFields(log.Fields{"httpListen": *listenAddress}).Info("Exporter listening")
 	log.Fatal(http.ListenAndServe(*listenAddress, nil))
+}
+
+// readRetryPolicyFromFlags builds the I/O and CRC retry policies from
+// their respective --onewire.retry.* flags.
+func readRetryPolicyFromFlags() ReadRetryPolicy {
+	return ReadRetryPolicy{
+		IO: RetryPolicy{
+			MaxAttempts:    *retryIOMaxAttempts,
+			InitialBackoff: *retryIOInitialBackoff,
+			MaxBackoff:     *retryIOMaxBackoff,
+			Multiplier:     *retryIOMultiplier,
+			JitterFraction: *retryIOJitterFraction,
+		},
+		CRC: RetryPolicy{
+			MaxAttempts:    *retryCRCMaxAttempts,
+			InitialBackoff: *retryCRCInitialBackoff,
+			MaxBackoff:     *retryCRCMaxBackoff,
+			Multiplier:     *retryCRCMultiplier,
+			JitterFraction: *retryCRCJitterFraction,
+		},
+	}
+}
 
+func newBuildInfoCollector() prometheus.Collector {
+	buildInfo := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "onewire_exporter_build_info",
+			Help: "A constant metric labeled with the exporter's build version, always 1.",
+		},
+		[]string{"version"},
+	)
+	buildInfo.WithLabelValues(version).Set(1)
+	return buildInfo
 }
 
 func rootPathHandler(w http.ResponseWriter, r *http.Request) {
@@ -98,87 +155,30 @@ func rootPathHandler(w http.ResponseWriter, r *http.Request) {
 		<body>
 		<h1>Node Exporter</h1>
 		<p><a href="`+*metricsPath+`">Metrics</a></p>
+		<p><a href="`+*processMetricsPath+`">Process Metrics</a></p>
 		<p><a href="`+*jsonMetricsPath+`">JSON Metrics</a></p>
 		</body>
 		</html>`)
 }
 
+// jsonPathHandler serves the most recent scrape of one target as JSON.
+// In single-bus mode (no --config.file), omit ?target=; in multi-target
+// mode, pass the same target name used on /metrics?target=.
 func jsonPathHandler(w http.ResponseWriter, r *http.Request) {
-	jsonData, _ := json.Marshal(sensors)
-	fmt.Fprintf(w, "%s", string(jsonData))
-}
-
-func observeOnewireTemperature() {
-	// lists onewire devices
-	err := createOnewireDeviceList()
-	if err != nil {
-		log.Fatal("Error getting Onewire device list")
-	}
-	for {
-		sensors = sensors[:len(onewireDeviceList)]
-		index := 0
-		for _, deviceID := range onewireDeviceList {
-			value, err := readOnewireDevicePayload(deviceID)
-			if err != nil {
-				log.WithFields(log.Fields{"deviceID": deviceID}).Error("Error reading from device")
-			}
-
-			fahrenheit := math.Round((value*1.8+32)*100) / 100
-			if *enableFahrenheit {
-				log.WithFields(log.Fields{"deviceID": deviceID, "value": value, "fahrenheit": fahrenheit, "hostname": hostname}).Info("Value read from device")
-			} else {
-				log.WithFields(log.Fields{"deviceID": deviceID, "value": value, "hostname": hostname}).Info("Value read from device")
-			}
-
-			onewireTemperatureC.With(prometheus.Labels{"device_id": deviceID, "hostname": hostname}).Set(value)
-			if *enableFahrenheit {
-				onewireTemperatureF.With(prometheus.Labels{"device_id": deviceID, "hostname": hostname}).Set(fahrenheit)
-			}
-
-			sensors[index] = sensor{SensorID: deviceID, SensorType: "temperature", SensorValue: value}
-			index++
-		}
-		time.Sleep(60 * time.Second)
-	}
-}
-
-func readOnewireDevicePayload(deviceID string) (float64, error) {
-	devicePayloadFile := fmt.Sprintf("%s%s/w1_slave", onewireDevicePath, deviceID)
-	re := regexp.MustCompile(`(?s).*YES.*t=(-?[0-9]+)`)
+	targetName := r.URL.Query().Get("target")
 
-	for retries := 0; retries < 5; retries++ {
-		buffer, err := ioutil.ReadFile(devicePayloadFile)
-		if err != nil {
-			log.WithFields(log.Fields{"devicePayloadFile": devicePayloadFile}).Error("Error reading Device")
-			return 0, err
-		}
-		match := re.FindStringSubmatch(string(buffer))
-		if len(match) > 0 {
-			value, _ := strconv.ParseFloat(match[1], 64)
-			return value / 1000, nil
-		}
-		log.WithFields(log.Fields{"deviceID": deviceID, "hostname": hostname}).Warning("Retrying read")
-		time.Sleep(1 * time.Second)
-	}
+	sensorsMu.Lock()
+	jsonData, _ := json.Marshal(sensorsByTarget[targetName])
+	sensorsMu.Unlock()
 
-	return 0, errors.New("Failed to read device")
+	fmt.Fprintf(w, "%s", string(jsonData))
 }
 
-func createOnewireDeviceList() error {
-	devices, err := ioutil.ReadDir(onewireDevicePath)
-	if err != nil {
-		log.Fatalf("Can't read device directory %v", err)
-		return nil
-	}
-	// searching for onewire attached devices
-	for _, device := range devices {
-		if strings.Contains(device.Name(), "w1_bus_master1") != true {
-			onewireDeviceList = append(onewireDeviceList, device.Name())
-			log.Infof("Device found: %s", device.Name())
-		}
-	}
-
-	sensors = make([]sensor, len(onewireDeviceList))
-
-	return nil
+// setSensors records the sensors seen on targetName's most recent
+// scrape ("" in single-bus mode). Safe to call from multiple
+// OnewireCollectors' Collect() concurrently.
+func setSensors(targetName string, scraped []sensor) {
+	sensorsMu.Lock()
+	sensorsByTarget[targetName] = scraped
+	sensorsMu.Unlock()
 }
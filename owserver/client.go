@@ -0,0 +1,192 @@
+// Package owserver implements a client for the owserver binary TCP
+// protocol (as spoken by owserver/OWFS on port 4304), so 1-wire devices
+// can be read from a host other than the one the bus is physically
+// attached to.
+package owserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Message types, per the owserver wire protocol.
+const (
+	msgError   = 0
+	msgNop     = 1
+	msgRead    = 2
+	msgWrite   = 3
+	msgDir     = 4
+	msgSize    = 5
+	msgPresent = 6
+	msgDirAll  = 7
+)
+
+// Control flags. Only the bits this client cares about are named; the
+// rest of the field is left zero.
+const (
+	flagPersistence  = 0x000004
+	flagBusRet       = 0x000002
+	defaultReadSize  = 8192
+	headerFieldCount = 6
+)
+
+// header is the fixed 24-byte header that precedes every owserver
+// request and response: version, payload length, return value/flags,
+// control flags, size and offset.
+type header struct {
+	Version      int32
+	Payload      int32
+	Ret          int32
+	ControlFlags int32
+	Size         int32
+	Offset       int32
+}
+
+// Client talks to a single owserver instance over TCP. It dials a fresh
+// connection per call and closes it once that call's response has been
+// read; requests still set the persistence flag, but no connection is
+// reused.
+type Client struct {
+	address string
+	timeout time.Duration
+}
+
+// NewClient returns a Client for the owserver instance listening on
+// address (host:port, e.g. "192.168.1.10:4304").
+func NewClient(address string) *Client {
+	return &Client{
+		address: address,
+		timeout: 5 * time.Second,
+	}
+}
+
+// Dir lists the entries under path (e.g. "/" for the bus root, or
+// "/28.FF1234567890/" for a device's attributes).
+func (c *Client) Dir(path string) ([]string, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := c.sendRequest(conn, msgDirAll, path, 0); err != nil {
+		return nil, err
+	}
+
+	resp, payload, err := c.readResponse(conn)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Ret < 0 {
+		return nil, fmt.Errorf("owserver: DIRALL %q failed with code %d", path, resp.Ret)
+	}
+	if len(payload) == 0 {
+		return nil, nil
+	}
+
+	return strings.Split(string(payload), ","), nil
+}
+
+// Read returns the raw contents of the attribute at path (e.g.
+// "/28.FF1234567890/temperature").
+func (c *Client) Read(path string) ([]byte, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := c.sendRequest(conn, msgRead, path, defaultReadSize); err != nil {
+		return nil, err
+	}
+
+	resp, payload, err := c.readResponse(conn)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Ret < 0 {
+		return nil, fmt.Errorf("owserver: READ %q failed with code %d", path, resp.Ret)
+	}
+
+	return payload, nil
+}
+
+// Present reports whether path exists on the bus.
+func (c *Client) Present(path string) (bool, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if err := c.sendRequest(conn, msgPresent, path, 0); err != nil {
+		return false, err
+	}
+
+	resp, _, err := c.readResponse(conn)
+	if err != nil {
+		return false, err
+	}
+
+	return resp.Ret >= 0, nil
+}
+
+func (c *Client) dial() (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", c.address, c.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("owserver: dialing %s: %w", c.address, err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (c *Client) sendRequest(conn net.Conn, msgType int32, path string, size int32) error {
+	pathBytes := append([]byte(path), 0)
+
+	req := header{
+		Version:      0,
+		Payload:      int32(len(pathBytes)),
+		Ret:          msgType,
+		ControlFlags: flagPersistence | flagBusRet,
+		Size:         size,
+		Offset:       0,
+	}
+
+	buf := new(bytes.Buffer)
+	if err := binary.Write(buf, binary.BigEndian, &req); err != nil {
+		return err
+	}
+	buf.Write(pathBytes)
+
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("owserver: writing request: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) readResponse(conn net.Conn) (header, []byte, error) {
+	var resp header
+	if err := binary.Read(conn, binary.BigEndian, &resp); err != nil {
+		return header{}, nil, fmt.Errorf("owserver: reading response header: %w", err)
+	}
+
+	if resp.Payload <= 0 {
+		return resp, nil, nil
+	}
+
+	payload := make([]byte, resp.Payload)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return header{}, nil, fmt.Errorf("owserver: reading response payload: %w", err)
+	}
+
+	return resp, bytes.TrimRight(payload, "\x00"), nil
+}
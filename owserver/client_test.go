@@ -0,0 +1,163 @@
+package owserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// wireHeader mirrors the unexported header struct for test fixtures,
+// so tests encode/decode the same 24-byte layout the client speaks.
+type wireHeader struct {
+	Version      int32
+	Payload      int32
+	Ret          int32
+	ControlFlags int32
+	Size         int32
+	Offset       int32
+}
+
+// startFakeOwserver runs a single-connection fake owserver: it reads
+// one request header+path off the connection, hands the path to
+// respond, and writes back whatever header+payload respond returns.
+func startFakeOwserver(t *testing.T, respond func(path string) (wireHeader, []byte)) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var req wireHeader
+		if err := binary.Read(conn, binary.BigEndian, &req); err != nil {
+			return
+		}
+		path := make([]byte, req.Payload)
+		if _, err := conn.Read(path); err != nil {
+			return
+		}
+
+		resp, payload := respond(string(bytes.TrimRight(path, "\x00")))
+		resp.Payload = int32(len(payload))
+		if err := binary.Write(conn, binary.BigEndian, &resp); err != nil {
+			return
+		}
+		conn.Write(payload)
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestClientReadSuccess(t *testing.T) {
+	address := startFakeOwserver(t, func(path string) (wireHeader, []byte) {
+		if path != "/28.FF1234567890/temperature" {
+			t.Errorf("server saw path %q", path)
+		}
+		return wireHeader{Ret: 0}, []byte("23.5\x00")
+	})
+
+	client := NewClient(address)
+	value, err := client.Read("/28.FF1234567890/temperature")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if string(value) != "23.5" {
+		t.Errorf("Read = %q, want %q", value, "23.5")
+	}
+}
+
+func TestClientReadErrorCode(t *testing.T) {
+	address := startFakeOwserver(t, func(path string) (wireHeader, []byte) {
+		return wireHeader{Ret: -1}, nil
+	})
+
+	client := NewClient(address)
+	if _, err := client.Read("/28.FF1234567890/temperature"); err == nil {
+		t.Error("expected an error for a negative Ret code")
+	}
+}
+
+func TestClientDir(t *testing.T) {
+	address := startFakeOwserver(t, func(path string) (wireHeader, []byte) {
+		return wireHeader{Ret: 0}, []byte("/10.1,/26.2,/uncached\x00")
+	})
+
+	client := NewClient(address)
+	entries, err := client.Dir("/")
+	if err != nil {
+		t.Fatalf("Dir: %v", err)
+	}
+	want := []string{"/10.1", "/26.2", "/uncached"}
+	if len(entries) != len(want) {
+		t.Fatalf("Dir = %v, want %v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entries[%d] = %q, want %q", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestClientDirEmpty(t *testing.T) {
+	address := startFakeOwserver(t, func(path string) (wireHeader, []byte) {
+		return wireHeader{Ret: 0}, nil
+	})
+
+	client := NewClient(address)
+	entries, err := client.Dir("/")
+	if err != nil {
+		t.Fatalf("Dir: %v", err)
+	}
+	if entries != nil {
+		t.Errorf("Dir = %v, want nil", entries)
+	}
+}
+
+func TestClientPresent(t *testing.T) {
+	present := startFakeOwserver(t, func(path string) (wireHeader, []byte) {
+		return wireHeader{Ret: 0}, nil
+	})
+
+	client := NewClient(present)
+	ok, err := client.Present("/28.FF1234567890")
+	if err != nil {
+		t.Fatalf("Present: %v", err)
+	}
+	if !ok {
+		t.Error("Present = false, want true for Ret >= 0")
+	}
+}
+
+func TestClientPresentMissing(t *testing.T) {
+	absent := startFakeOwserver(t, func(path string) (wireHeader, []byte) {
+		return wireHeader{Ret: -1}, nil
+	})
+
+	client := NewClient(absent)
+	ok, err := client.Present("/28.FF1234567890")
+	if err != nil {
+		t.Fatalf("Present: %v", err)
+	}
+	if ok {
+		t.Error("Present = true, want false for Ret < 0")
+	}
+}
+
+func TestClientDialTimeout(t *testing.T) {
+	// Port 1 is reserved and nothing listens there, so the dial should
+	// fail fast rather than hang for the full default timeout.
+	client := &Client{address: "127.0.0.1:1", timeout: 50 * time.Millisecond}
+	if _, err := client.Read("/whatever"); err == nil {
+		t.Error("expected an error connecting to a closed port")
+	}
+}
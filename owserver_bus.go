@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jimbrowne/onewire-prom-exporter/owserver"
+)
+
+// deviceIDRegexp matches a 1-wire device ID (2 hex digit family code,
+// dot, 12 hex digit serial), filtering out the pseudo-entries owserver
+// returns alongside real devices on a DIRALL of "/", e.g. "uncached",
+// "settings", "system", "statistics", "structure", "simultaneous".
+var deviceIDRegexp = regexp.MustCompile(`^[0-9A-Fa-f]{2}\.[0-9A-Fa-f]{12}$`)
+
+// owserverBus reads devices from a remote owserver instance over its
+// binary TCP protocol, as an alternative to the local sysfs backend.
+// owserver has no notion of a CRC check of its own, so only the I/O
+// retry budget applies here.
+type owserverBus struct {
+	client      *owserver.Client
+	retryPolicy RetryPolicy
+}
+
+func newOwserverBus(address string, retryPolicy ReadRetryPolicy) *owserverBus {
+	return &owserverBus{client: owserver.NewClient(address), retryPolicy: retryPolicy.IO}
+}
+
+func (b *owserverBus) ListDevices() ([]string, error) {
+	entries, err := b.client.Dir("/")
+	if err != nil {
+		return nil, &ReadError{Reason: ReasonIO, Err: err}
+	}
+
+	devices := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		id := strings.Trim(entry, "/")
+		if !deviceIDRegexp.MatchString(id) {
+			continue
+		}
+		devices = append(devices, id)
+	}
+	return devices, nil
+}
+
+func (b *owserverBus) ReadAttribute(deviceID, attribute string) (string, error) {
+	path := fmt.Sprintf("/%s/%s", deviceID, owserverAttributeName(attribute))
+
+	// Always make at least one attempt, then check the limit, like
+	// readOnewireTemperatureAttribute's retry loop: a for-condition loop
+	// would silently skip the body (and return a fabricated "", nil
+	// success) if MaxAttempts is ever 0 or negative.
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		raw, err := b.client.Read(path)
+		if err == nil {
+			return strings.TrimSpace(string(raw)), nil
+		}
+		lastErr = &ReadError{Reason: ReasonIO, Err: err}
+		if attempt+1 >= b.retryPolicy.MaxAttempts {
+			return "", lastErr
+		}
+		time.Sleep(b.retryPolicy.Backoff(attempt))
+	}
+}
+
+// owserverAttributeName translates a logical attribute name to what
+// OWFS actually calls it: "counter.A"/"counter.B" become the real
+// "counters.A"/"counters.B" properties, and a DS2408 "gpio.N" becomes
+// OWFS's per-pin "sensed.N" property. Everything else (temperature,
+// vdd, vad) is already named the same on both backends.
+func owserverAttributeName(attribute string) string {
+	switch {
+	case attribute == "counter.A":
+		return "counters.A"
+	case attribute == "counter.B":
+		return "counters.B"
+	case strings.HasPrefix(attribute, "gpio."):
+		return "sensed." + strings.TrimPrefix(attribute, "gpio.")
+	default:
+		return attribute
+	}
+}
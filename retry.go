@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Reasons a device read can fail, used to label onewire_read_errors_total.
+const (
+	ReasonIO  = "io"
+	ReasonCRC = "crc"
+)
+
+// ReadError wraps a device read failure with a machine-readable reason
+// so the collector can label onewire_read_errors_total appropriately.
+type ReadError struct {
+	Reason string
+	Err    error
+}
+
+func (e *ReadError) Error() string { return e.Err.Error() }
+func (e *ReadError) Unwrap() error { return e.Err }
+
+// RetryPolicy controls how many times, and how long, to keep retrying a
+// failed read before giving up.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	JitterFraction float64
+}
+
+// Backoff returns how long to sleep after the attempt-th failure
+// (0-indexed) before trying again.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.JitterFraction > 0 {
+		backoff += backoff * p.JitterFraction * (rand.Float64()*2 - 1)
+	}
+	if backoff < 0 {
+		backoff = 0
+	}
+	return time.Duration(backoff)
+}
+
+// ReadRetryPolicy gives I/O failures (file unreadable, device
+// disappeared, network error) and CRC failures (a reading was present
+// but failed its checksum) independent retry budgets, since a vanished
+// device is unlikely to come back on the next attempt while a CRC miss
+// often clears up within a second or two.
+type ReadRetryPolicy struct {
+	IO  RetryPolicy
+	CRC RetryPolicy
+}
+
+// DefaultReadRetryPolicy is the policy used when none of the
+// --onewire.retry.* flags are set: five attempts, one second apart, no
+// backoff growth, for both classes. It also supplies those flags'
+// defaults, so the flag help text and the compiled-in default can't
+// drift apart.
+func DefaultReadRetryPolicy() ReadRetryPolicy {
+	fixedOneSecond := RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Second,
+		Multiplier:     1,
+	}
+	return ReadRetryPolicy{IO: fixedOneSecond, CRC: fixedOneSecond}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoffExponentialGrowth(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+	}
+
+	cases := map[int]time.Duration{
+		0: 100 * time.Millisecond,
+		1: 200 * time.Millisecond,
+		2: 400 * time.Millisecond,
+		3: 800 * time.Millisecond,
+	}
+	for attempt, want := range cases {
+		if got := policy.Backoff(attempt); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestRetryPolicyBackoffCapsAtMaxBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     3 * time.Second,
+		Multiplier:     2,
+	}
+
+	if got := policy.Backoff(5); got != 3*time.Second {
+		t.Errorf("Backoff(5) = %v, want capped at %v", got, 3*time.Second)
+	}
+}
+
+func TestRetryPolicyBackoffNoMultiplierGrowth(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Second,
+		Multiplier:     1,
+	}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		if got := policy.Backoff(attempt); got != time.Second {
+			t.Errorf("Backoff(%d) = %v, want %v", attempt, got, time.Second)
+		}
+	}
+}
+
+func TestRetryPolicyBackoffJitterStaysWithinBounds(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Second,
+		Multiplier:     1,
+		JitterFraction: 0.5,
+	}
+
+	for i := 0; i < 100; i++ {
+		got := policy.Backoff(0)
+		if got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("Backoff(0) = %v, want within [0.5s, 1.5s]", got)
+		}
+	}
+}
+
+func TestRetryPolicyBackoffNeverNegative(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Multiplier:     1,
+		JitterFraction: 1,
+	}
+
+	for i := 0; i < 100; i++ {
+		if got := policy.Backoff(0); got < 0 {
+			t.Fatalf("Backoff(0) = %v, want >= 0", got)
+		}
+	}
+}
+
+func TestDefaultReadRetryPolicy(t *testing.T) {
+	policy := DefaultReadRetryPolicy()
+
+	for _, p := range []RetryPolicy{policy.IO, policy.CRC} {
+		if p.MaxAttempts != 5 {
+			t.Errorf("MaxAttempts = %d, want 5", p.MaxAttempts)
+		}
+		if p.InitialBackoff != time.Second || p.MaxBackoff != time.Second {
+			t.Errorf("backoff = %v/%v, want %v/%v", p.InitialBackoff, p.MaxBackoff, time.Second, time.Second)
+		}
+		if p.Multiplier != 1 {
+			t.Errorf("Multiplier = %v, want 1", p.Multiplier)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// multiTargetMetricsHandler implements the Prometheus "multi-target
+// exporter" pattern: the scrape job picks which configured target to
+// read from via /metrics?target=<name>, so one exporter instance can
+// stand in for many per-host exporters.
+//
+// It builds one OnewireCollector per configured target up front and
+// reuses it across requests, so each target's onewire_scrape_errors_total
+// and onewire_read_errors_total counters accumulate across scrapes
+// instead of resetting to zero on every request; only the underlying
+// bus read happens fresh per scrape, inside Collect.
+func multiTargetMetricsHandler(cfg *Config, retryPolicy ReadRetryPolicy) (http.HandlerFunc, error) {
+	registries := make(map[string]*prometheus.Registry, len(cfg.Targets))
+	for _, target := range cfg.Targets {
+		bus, err := newOnewireBus(target.Module, target.Address, target.Address, retryPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %w", target.Name, err)
+		}
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(NewOnewireCollector(bus, *enableFahrenheit, target.Devices, target.Name))
+		registries[target.Name] = registry
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		targetName := r.URL.Query().Get("target")
+		if targetName == "" {
+			http.Error(w, "target parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		registry, ok := registries[targetName]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown target %q", targetName), http.StatusNotFound)
+			return
+		}
+
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fastTestRetryPolicy() ReadRetryPolicy {
+	p := RetryPolicy{MaxAttempts: 1, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1}
+	return ReadRetryPolicy{IO: p, CRC: p}
+}
+
+func TestMultiTargetMetricsHandlerRequiresTarget(t *testing.T) {
+	cfg := &Config{Targets: []TargetConfig{{Name: "garage", Module: "sysfs", Address: t.TempDir() + "/"}}}
+	handler, err := multiTargetMetricsHandler(cfg, fastTestRetryPolicy())
+	if err != nil {
+		t.Fatalf("multiTargetMetricsHandler: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != 400 {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestMultiTargetMetricsHandlerUnknownTarget(t *testing.T) {
+	cfg := &Config{Targets: []TargetConfig{{Name: "garage", Module: "sysfs", Address: t.TempDir() + "/"}}}
+	handler, err := multiTargetMetricsHandler(cfg, fastTestRetryPolicy())
+	if err != nil {
+		t.Fatalf("multiTargetMetricsHandler: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/metrics?target=shed", nil))
+	if rec.Code != 404 {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestMultiTargetMetricsHandlerUnknownBackend(t *testing.T) {
+	cfg := &Config{Targets: []TargetConfig{{Name: "garage", Module: "bogus", Address: "somewhere"}}}
+	if _, err := multiTargetMetricsHandler(cfg, fastTestRetryPolicy()); err == nil {
+		t.Error("expected an error building a handler for an unknown backend")
+	}
+}
+
+// TestMultiTargetMetricsHandlerCountersPersistAcrossScrapes guards
+// against rebuilding the collector (and its _total counters) on every
+// request: a device that fails to read should show an increasing
+// onewire_scrape_errors_total across repeated scrapes of the same
+// target, not reset to 1 each time.
+func TestMultiTargetMetricsHandlerCountersPersistAcrossScrapes(t *testing.T) {
+	devicePath := t.TempDir() + "/"
+	writeDeviceFile(t, devicePath, "28-0000061f2eba", "placeholder", "")
+
+	cfg := &Config{Targets: []TargetConfig{{Name: "garage", Module: "sysfs", Address: devicePath}}}
+	handler, err := multiTargetMetricsHandler(cfg, fastTestRetryPolicy())
+	if err != nil {
+		t.Fatalf("multiTargetMetricsHandler: %v", err)
+	}
+
+	scrape := func() string {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest("GET", "/metrics?target=garage", nil))
+		body, _ := io.ReadAll(rec.Result().Body)
+		return string(body)
+	}
+
+	first := scrape()
+	if !strings.Contains(first, `onewire_scrape_errors_total{device_id="28-0000061f2eba"} 1`) {
+		t.Fatalf("first scrape missing error counter at 1:\n%s", first)
+	}
+
+	second := scrape()
+	if !strings.Contains(second, `onewire_scrape_errors_total{device_id="28-0000061f2eba"} 2`) {
+		t.Fatalf("second scrape's error counter didn't accumulate to 2 (collector was rebuilt per request):\n%s", second)
+	}
+}